@@ -0,0 +1,104 @@
+// Package connstate tracks per-connection request counts and age via
+// http.Server's ConnContext hook, so the demo server can enforce and report
+// its own keep-alive limits.
+package connstate
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// State is attached to a connection's context by NewConnContext and updated
+// on every request served over that connection.
+type State struct {
+	RemoteAddr string
+	FirstSeen  time.Time
+	requests   int64 // atomic
+}
+
+// IncRequests records another request on this connection and returns the new
+// total.
+func (s *State) IncRequests() int64 {
+	return atomic.AddInt64(&s.requests, 1)
+}
+
+// Requests returns the number of requests served on this connection so far.
+func (s *State) Requests() int64 {
+	return atomic.LoadInt64(&s.requests)
+}
+
+// Age returns how long this connection has been open.
+func (s *State) Age() time.Duration {
+	return time.Since(s.FirstSeen)
+}
+
+type ctxKey struct{}
+
+// NewConnContext is an http.Server.ConnContext hook that attaches a fresh
+// State to every new connection.
+func NewConnContext(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, ctxKey{}, &State{
+		RemoteAddr: c.RemoteAddr().String(),
+		FirstSeen:  time.Now(),
+	})
+}
+
+// FromContext returns the State attached by NewConnContext, or nil if none
+// was attached (e.g. when the server's ConnContext hook isn't configured).
+func FromContext(ctx context.Context) *State {
+	state, _ := ctx.Value(ctxKey{}).(*State)
+	return state
+}
+
+// Middleware stamps every response with X-Conn-Requests and X-Conn-Age-Ms,
+// and sets Connection: close once the connection has crossed maxRequests
+// requests or maxDuration of age. A zero threshold disables that check.
+func Middleware(maxRequests int, maxDuration time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state := FromContext(r.Context())
+		if state == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		count := state.IncRequests()
+		age := state.Age()
+
+		w.Header().Set("X-Conn-Requests", strconv.FormatInt(count, 10))
+		w.Header().Set("X-Conn-Age-Ms", strconv.FormatInt(age.Milliseconds(), 10))
+
+		if (maxRequests > 0 && count >= int64(maxRequests)) || (maxDuration > 0 && age >= maxDuration) {
+			w.Header().Set("Connection", "close")
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// snapshot is the JSON shape returned by ServeConn.
+type snapshot struct {
+	RemoteAddr string `json:"remote_addr"`
+	Requests   int64  `json:"requests"`
+	AgeMs      int64  `json:"age_ms"`
+}
+
+// ServeConn writes the requesting connection's State as JSON.
+func ServeConn(w http.ResponseWriter, r *http.Request) {
+	state := FromContext(r.Context())
+	if state == nil {
+		http.Error(w, "no connection state available", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snapshot{
+		RemoteAddr: state.RemoteAddr,
+		Requests:   state.Requests(),
+		AgeMs:      state.Age().Milliseconds(),
+	})
+}
@@ -0,0 +1,90 @@
+package connstate
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func withState(req *http.Request, state *State) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), ctxKey{}, state))
+}
+
+func TestMiddlewareThresholds(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxRequests    int
+		maxDuration    time.Duration
+		existingCount  int64
+		connAge        time.Duration
+		wantConnClose  bool
+		wantReqsHeader string
+	}{
+		{"under every threshold", 5, time.Minute, 1, time.Second, false, "2"},
+		{"hits request threshold", 3, 0, 2, time.Second, true, "3"},
+		{"hits duration threshold", 0, time.Second, 1, 2 * time.Second, true, "2"},
+		{"zero thresholds never close", 0, 0, 100, time.Hour, false, "101"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			handler := Middleware(tt.maxRequests, tt.maxDuration, next)
+
+			state := &State{RemoteAddr: "1.2.3.4:5678", FirstSeen: time.Now().Add(-tt.connAge)}
+			state.requests = tt.existingCount
+
+			req := withState(httptest.NewRequest(http.MethodGet, "/", nil), state)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("X-Conn-Requests"); got != tt.wantReqsHeader {
+				t.Errorf("X-Conn-Requests = %q, want %q", got, tt.wantReqsHeader)
+			}
+			gotClose := rec.Header().Get("Connection") == "close"
+			if gotClose != tt.wantConnClose {
+				t.Errorf("Connection: close = %v, want %v", gotClose, tt.wantConnClose)
+			}
+		})
+	}
+}
+
+func TestMiddlewarePassesThroughWithoutState(t *testing.T) {
+	called := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+	handler := Middleware(1, time.Second, next)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if !called {
+		t.Fatal("expected next to be called when no connection state is present")
+	}
+	if rec.Header().Get("X-Conn-Requests") != "" {
+		t.Error("expected no X-Conn-Requests header without connection state")
+	}
+}
+
+func TestServeConnWritesJSON(t *testing.T) {
+	state := &State{RemoteAddr: "1.2.3.4:5678", FirstSeen: time.Now()}
+	state.IncRequests()
+
+	req := withState(httptest.NewRequest(http.MethodGet, "/conn", nil), state)
+	rec := httptest.NewRecorder()
+	ServeConn(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if want := `"remote_addr":"1.2.3.4:5678"`; !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("body = %q, want it to contain %q", rec.Body.String(), want)
+	}
+}
@@ -1,14 +1,78 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"log"
 	"math/rand"
 	"net/http"
 	"time"
+
+	"github.com/traefik-tech-blog/traefik-traffic-middlewares/05-circuit-breaker/connstate"
+	"github.com/traefik-tech-blog/traefik-traffic-middlewares/05-circuit-breaker/panics"
+	"github.com/traefik-tech-blog/traefik-traffic-middlewares/05-circuit-breaker/profile"
+	"github.com/traefik-tech-blog/traefik-traffic-middlewares/05-circuit-breaker/scripts"
+	"github.com/traefik-tech-blog/traefik-traffic-middlewares/05-circuit-breaker/stats"
 )
 
 func main() {
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+	scriptPath := flag.String("script", "", "path to a Lua script that decides how / responds (overrides the built-in random latency/error logic)")
+	profilePath := flag.String("profile", "", "path to a YAML traffic profile (overrides the built-in random latency/error logic; hot-reloads on SIGHUP)")
+	panicRate := flag.Int("panicRate", 0, "percent chance (0-100) that a request to / panics instead of being handled")
+	noRecover := flag.Bool("noRecover", false, "disable our own panic recovery so net/http's raw recovery behavior shows through")
+	maxRequestsPerConn := flag.Int("maxRequestsPerConn", 0, "close the connection after it has served this many requests (0 = unlimited)")
+	maxConnDuration := flag.Duration("maxConnDuration", 0, "close the connection once it has been open this long (0 = unlimited)")
+	flag.Parse()
+
+	recorder := stats.NewRecorder()
+
+	root := panics.Inject(*panicRate, defaultHandler(*scriptPath, *profilePath))
+	root = panics.Recover(*noRecover, root)
+
+	mux := http.NewServeMux()
+	mux.Handle("/", root)
+	mux.Handle("/panic", panics.Recover(*noRecover, http.HandlerFunc(panics.Handler)))
+	mux.HandleFunc("/stats", recorder.ServeStats)
+	mux.HandleFunc("/metrics", recorder.ServeMetrics)
+	mux.HandleFunc("/conn", connstate.ServeConn)
+
+	handler := connstate.Middleware(*maxRequestsPerConn, *maxConnDuration, recorder.Middleware(mux))
+
+	server := &http.Server{
+		Addr:        ":80",
+		Handler:     handler,
+		ConnContext: connstate.NewConnContext,
+	}
+	server.ListenAndServe()
+}
+
+// helloHandler is the plain success response served once latency/error
+// injection (whichever mode is active) has decided not to intervene.
+func helloHandler(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintln(w, "Hello, World!")
+}
+
+// defaultHandler picks / 's behavior in priority order: a YAML traffic
+// profile, then a Lua script, then the built-in random latency/error logic.
+func defaultHandler(scriptPath, profilePath string) http.Handler {
+	if profilePath != "" {
+		controller, err := profile.Load(profilePath)
+		if err != nil {
+			log.Fatalf("profile: failed to load %s: %v", profilePath, err)
+		}
+		controller.Watch()
+		return controller.Middleware("/", http.HandlerFunc(helloHandler))
+	}
+
+	if scriptPath != "" {
+		engine, err := scripts.Load(scriptPath)
+		if err != nil {
+			log.Fatalf("scripts: failed to load %s: %v", scriptPath, err)
+		}
+		return engine.Handler()
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Simulate a random latency between 0 and 1s.
 		time.Sleep(time.Duration(rand.Intn(1000)) * time.Millisecond)
 
@@ -28,6 +92,4 @@ func main() {
 
 		fmt.Fprintln(w, "Hello, World!")
 	})
-
-	http.ListenAndServe(":80", nil)
 }
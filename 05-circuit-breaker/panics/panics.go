@@ -0,0 +1,97 @@
+// Package panics turns the demo server into a fixture for exercising a
+// recovery middleware: it can inject panics probabilistically, expose a
+// dedicated /panic endpoint, and optionally disable its own recovery so
+// net/http's raw (abrupt) behavior shows through instead.
+package panics
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+)
+
+// customPanic is one of the varied panic payloads Handler and Inject can
+// produce, to exercise recovery middlewares that type-switch on the
+// recovered value.
+type customPanic struct {
+	Reason string
+}
+
+func (p customPanic) Error() string {
+	return "custom panic: " + p.Reason
+}
+
+// errNilPanic stands in for a bare panic(nil). recover() reports whether a
+// panic happened at all, and relying on "recovered value == nil" to mean "no
+// panic" only works on Go >=1.21, where the runtime wraps panic(nil) in a
+// non-nil *runtime.PanicNilError; on older toolchains it's genuinely nil and
+// would be silently swallowed. Panicking with this sentinel instead keeps
+// the "some code panicked with nil" scenario version-independent.
+var errNilPanic = errors.New("deliberate nil panic")
+
+// payload returns one of five representative panic values: a string, an
+// error, http.ErrAbortHandler, errNilPanic (standing in for panic(nil)), and
+// a custom struct.
+func payload() interface{} {
+	switch rand.Intn(5) {
+	case 0:
+		return "deliberate panic"
+	case 1:
+		return fmt.Errorf("deliberate panic error")
+	case 2:
+		return http.ErrAbortHandler
+	case 3:
+		return errNilPanic
+	default:
+		return customPanic{Reason: "injected by panics.Handler"}
+	}
+}
+
+// Handler always panics with a varied payload, for exercising a recovery
+// middleware on demand.
+func Handler(w http.ResponseWriter, r *http.Request) {
+	panic(payload())
+}
+
+// Inject wraps next with a probabilistic panic injector: ratePercent out of
+// 100 requests panic with a varied payload instead of reaching next. A
+// ratePercent of 0 disables injection entirely.
+func Inject(ratePercent int, next http.Handler) http.Handler {
+	if ratePercent <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rand.Intn(100) < ratePercent {
+			panic(payload())
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Recover wraps next with Traefik-style panic recovery: http.ErrAbortHandler
+// is re-panicked so net/http can abort the connection silently, exactly as
+// it would without this middleware; any other panic is recovered and turned
+// into a 500 response. When noRecover is true, Recover is a no-op and next's
+// panics propagate straight to net/http's own (connection-aborting) recovery,
+// so callers can compare the two behaviors.
+func Recover(noRecover bool, next http.Handler) http.Handler {
+	if noRecover {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			log.Printf("panics: recovered from panic in %s %s: %v", r.Method, r.URL.Path, rec)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
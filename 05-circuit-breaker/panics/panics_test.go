@@ -0,0 +1,88 @@
+package panics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecoverTurnsPanicsInto500(t *testing.T) {
+	tests := []struct {
+		name  string
+		panic interface{}
+	}{
+		{"string panic", "boom"},
+		{"error panic", errNilPanic},
+		{"custom struct panic", customPanic{Reason: "test"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Recover(false, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				panic(tt.panic)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusInternalServerError {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+			}
+		})
+	}
+}
+
+func TestRecoverRepanicsErrAbortHandler(t *testing.T) {
+	handler := Recover(false, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	defer func() {
+		if rec := recover(); rec != http.ErrAbortHandler {
+			t.Fatalf("recovered value = %v, want http.ErrAbortHandler", rec)
+		}
+	}()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	t.Fatal("expected ServeHTTP to panic with http.ErrAbortHandler")
+}
+
+func TestRecoverNoRecoverIsANoOp(t *testing.T) {
+	handler := Recover(true, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	defer func() {
+		if rec := recover(); rec != "boom" {
+			t.Fatalf("recovered value = %v, want %q", rec, "boom")
+		}
+	}()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	t.Fatal("expected ServeHTTP to panic since noRecover disables our middleware")
+}
+
+func TestInjectRespectsRate(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	handler := Inject(0, next)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("rate 0: status = %d, want %d (no injection)", rec.Code, http.StatusOK)
+	}
+
+	handler = Inject(100, next)
+	defer func() {
+		if recover() == nil {
+			t.Fatal("rate 100: expected a panic on every request")
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+}
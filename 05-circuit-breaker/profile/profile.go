@@ -0,0 +1,264 @@
+// Package profile replaces the demo server's hardcoded uniform latency and
+// fixed error rates with per-route distributions loaded from a YAML file.
+// The active profile can be hot-reloaded on SIGHUP so traffic shapes can be
+// changed without restarting the backend.
+package profile
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Latency describes one of the supported latency distribution shapes.
+// Only the fields relevant to Type need be set in the YAML document.
+type Latency struct {
+	Type string `yaml:"type"` // uniform, normal, lognormal, bimodal, pareto
+
+	Min, Max float64 // uniform
+	Mean     float64 // normal
+	StdDev   float64 // normal
+	Mu       float64 // lognormal
+	Sigma    float64 // lognormal
+	P        float64 // bimodal: probability of the A mode
+	A, B     float64 // bimodal: the two modal latencies, in ms
+	Scale    float64 // pareto
+	Shape    float64 // pareto
+}
+
+// Sample draws one latency value in milliseconds from the distribution.
+func (l Latency) Sample() time.Duration {
+	var ms float64
+	switch l.Type {
+	case "uniform":
+		ms = l.Min + rand.Float64()*(l.Max-l.Min)
+	case "normal":
+		ms = rand.NormFloat64()*l.StdDev + l.Mean
+	case "lognormal":
+		ms = math.Exp(rand.NormFloat64()*l.Sigma + l.Mu)
+	case "bimodal":
+		if rand.Float64() < l.P {
+			ms = l.A
+		} else {
+			ms = l.B
+		}
+	case "pareto":
+		ms = l.Scale / math.Pow(rand.Float64(), 1/l.Shape)
+	}
+	if ms < 0 {
+		ms = 0
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// ErrorBucket is one weighted outcome of error injection: Weight percent of
+// requests get Status, with an optional Retry-After header.
+type ErrorBucket struct {
+	Status     int     `yaml:"status"`
+	Weight     float64 `yaml:"weight"`
+	RetryAfter int     `yaml:"retryAfter,omitempty"`
+}
+
+// Burst makes N consecutive requests fail once every K requests.
+type Burst struct {
+	N int `yaml:"n"`
+	K int `yaml:"k"`
+}
+
+// Chaos flips the route between its normal error regime and a degraded one
+// (errors five times more likely) every IntervalSeconds.
+type Chaos struct {
+	IntervalSeconds int `yaml:"intervalSeconds"`
+}
+
+// degradedMultiplier is how much Chaos mode scales error weights by while in
+// its degraded regime.
+const degradedMultiplier = 5
+
+// Route is the full traffic shape for a single route.
+type Route struct {
+	Latency Latency       `yaml:"latency"`
+	Errors  []ErrorBucket `yaml:"errors"`
+	Burst   *Burst        `yaml:"burst,omitempty"`
+	Chaos   *Chaos        `yaml:"chaos,omitempty"`
+}
+
+// Profile is the top-level shape of a profile.yaml file: one Route per path.
+type Profile struct {
+	Routes map[string]Route `yaml:"routes"`
+}
+
+// parse decodes a Profile from YAML bytes.
+func parse(data []byte) (*Profile, error) {
+	var p Profile
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// routeState tracks the per-route counters (burst cycle position, chaos
+// start time) needed to apply Burst and Chaos across requests.
+type routeState struct {
+	requestCount int64 // atomic, total requests seen on this route
+	chaosStart   time.Time
+}
+
+// Controller owns the active Profile, reloading it from disk on SIGHUP.
+type Controller struct {
+	path    string
+	current atomic.Value // *Profile
+
+	statesMu sync.Mutex
+	states   map[string]*routeState
+}
+
+// Load reads and parses path, returning a Controller that serves it. Call
+// Watch to enable SIGHUP hot-reload.
+func Load(path string) (*Controller, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Controller{path: path, states: make(map[string]*routeState)}
+	for route := range p.Routes {
+		c.states[route] = &routeState{chaosStart: time.Now()}
+	}
+	c.current.Store(p)
+	return c, nil
+}
+
+// Watch reloads the profile from disk every time the process receives
+// SIGHUP. A reload error is logged and the previous profile keeps serving.
+func (c *Controller) Watch() {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	go func() {
+		for range sighup {
+			data, err := ioutil.ReadFile(c.path)
+			if err != nil {
+				log.Printf("profile: reload of %s failed: %v", c.path, err)
+				continue
+			}
+			p, err := parse(data)
+			if err != nil {
+				log.Printf("profile: reload of %s failed: %v", c.path, err)
+				continue
+			}
+
+			c.statesMu.Lock()
+			for route := range p.Routes {
+				if _, ok := c.states[route]; !ok {
+					c.states[route] = &routeState{chaosStart: time.Now()}
+				}
+			}
+			c.statesMu.Unlock()
+
+			c.current.Store(p)
+			log.Printf("profile: reloaded %s", c.path)
+		}
+	}()
+}
+
+// stateFor returns the routeState for route, which is safe to read and
+// mutate concurrently with Watch's reload goroutine.
+func (c *Controller) stateFor(route string) *routeState {
+	c.statesMu.Lock()
+	defer c.statesMu.Unlock()
+	return c.states[route]
+}
+
+// Middleware applies the distribution configured for route: it sleeps for a
+// sampled latency, then either serves next or writes a weighted error
+// response. Requests to a route with no configured entry fall straight
+// through to next.
+func (c *Controller) Middleware(route string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := c.current.Load().(*Profile)
+		cfg, ok := p.Routes[route]
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		time.Sleep(cfg.Latency.Sample())
+
+		state := c.stateFor(route)
+		if bucket, ok := pickError(cfg, state); ok {
+			if bucket.RetryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(bucket.RetryAfter))
+			}
+			w.WriteHeader(bucket.Status)
+			fmt.Fprintln(w, http.StatusText(bucket.Status))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// pickError decides whether this request should fail, applying Burst and
+// Chaos on top of the route's weighted error buckets.
+func pickError(cfg Route, state *routeState) (ErrorBucket, bool) {
+	count := atomic.AddInt64(&state.requestCount, 1)
+
+	if cfg.Burst != nil && cfg.Burst.K > 0 && cfg.Burst.N > 0 {
+		pos := (count - 1) % int64(cfg.Burst.K)
+		if pos < int64(cfg.Burst.N) && len(cfg.Errors) > 0 {
+			return cfg.Errors[0], true
+		}
+	}
+
+	if len(cfg.Errors) == 0 {
+		return ErrorBucket{}, false
+	}
+
+	multiplier := chaosMultiplier(cfg.Chaos, state)
+	return weightedPick(cfg.Errors, multiplier, rand.Float64()*100)
+}
+
+// chaosMultiplier returns degradedMultiplier while the route is in its
+// degraded regime (toggling every IntervalSeconds since state.chaosStart),
+// and 1 otherwise, or when chaos isn't configured.
+func chaosMultiplier(chaos *Chaos, state *routeState) float64 {
+	if chaos == nil || chaos.IntervalSeconds <= 0 {
+		return 1
+	}
+	elapsed := time.Since(state.chaosStart)
+	slot := int64(elapsed/time.Second) / int64(chaos.IntervalSeconds)
+	if slot%2 == 1 {
+		return degradedMultiplier
+	}
+	return 1
+}
+
+// weightedPick walks errors in order, scaling each Weight by multiplier, and
+// returns the first bucket whose cumulative weight exceeds roll (expected to
+// be drawn from [0, 100)).
+func weightedPick(errors []ErrorBucket, multiplier, roll float64) (ErrorBucket, bool) {
+	var cumulative float64
+	for _, bucket := range errors {
+		cumulative += bucket.Weight * multiplier
+		if roll < cumulative {
+			return bucket, true
+		}
+	}
+	return ErrorBucket{}, false
+}
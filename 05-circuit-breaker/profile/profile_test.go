@@ -0,0 +1,103 @@
+package profile
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPickErrorBurst(t *testing.T) {
+	cfg := Route{
+		Errors: []ErrorBucket{{Status: 503, Weight: 0}}, // weight 0: only Burst should ever trigger it
+		Burst:  &Burst{N: 2, K: 5},
+	}
+	state := &routeState{chaosStart: time.Now()}
+
+	// Burst fails requests 1-2 of every 5, then lets 3-5 through.
+	want := []bool{true, true, false, false, false, true, true, false, false, false}
+	for i, wantFail := range want {
+		_, failed := pickError(cfg, state)
+		if failed != wantFail {
+			t.Errorf("request %d: pickError failed=%v, want %v", i+1, failed, wantFail)
+		}
+	}
+}
+
+func TestChaosMultiplier(t *testing.T) {
+	tests := []struct {
+		name  string
+		chaos *Chaos
+		ago   time.Duration
+		want  float64
+	}{
+		{"no chaos configured", nil, 0, 1},
+		{"zero interval disables chaos", &Chaos{IntervalSeconds: 0}, 100 * time.Second, 1},
+		{"first interval is normal", &Chaos{IntervalSeconds: 10}, 5 * time.Second, 1},
+		{"second interval is degraded", &Chaos{IntervalSeconds: 10}, 15 * time.Second, degradedMultiplier},
+		{"third interval is normal again", &Chaos{IntervalSeconds: 10}, 25 * time.Second, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := &routeState{chaosStart: time.Now().Add(-tt.ago)}
+			if got := chaosMultiplier(tt.chaos, state); got != tt.want {
+				t.Errorf("chaosMultiplier() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWeightedPick(t *testing.T) {
+	errors := []ErrorBucket{
+		{Status: 500, Weight: 5},
+		{Status: 503, Weight: 2},
+		{Status: 404, Weight: 3},
+	}
+
+	tests := []struct {
+		name       string
+		roll       float64
+		multiplier float64
+		wantStatus int
+		wantOK     bool
+	}{
+		{"roll lands in first bucket", 2, 1, 500, true},
+		{"roll lands in second bucket", 6, 1, 503, true},
+		{"roll lands in third bucket", 9, 1, 404, true},
+		{"roll past every bucket is a pass", 99, 1, 0, false},
+		{"degraded multiplier widens the buckets", 20, degradedMultiplier, 500, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucket, ok := weightedPick(errors, tt.multiplier, tt.roll)
+			if ok != tt.wantOK {
+				t.Fatalf("weightedPick() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && bucket.Status != tt.wantStatus {
+				t.Errorf("weightedPick() status = %d, want %d", bucket.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestLatencySample(t *testing.T) {
+	tests := []struct {
+		name     string
+		latency  Latency
+		min, max time.Duration
+	}{
+		{"uniform", Latency{Type: "uniform", Min: 10, Max: 20}, 10 * time.Millisecond, 20 * time.Millisecond},
+		{"bimodal picks a or b", Latency{Type: "bimodal", P: 1, A: 5, B: 500}, 5 * time.Millisecond, 5 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 50; i++ {
+				got := tt.latency.Sample()
+				if got < tt.min || got > tt.max {
+					t.Fatalf("Sample() = %v, want within [%v, %v]", got, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
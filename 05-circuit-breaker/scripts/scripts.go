@@ -0,0 +1,160 @@
+// Package scripts lets a Lua script decide how the demo server responds to
+// each request, in place of the hardcoded random latency/error logic. The
+// script is compiled once at startup and re-run for every request.
+package scripts
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// Engine holds a compiled Lua script and runs it once per request against a
+// fresh *lua.LState.
+type Engine struct {
+	proto *lua.FunctionProto
+}
+
+// Load reads and compiles the Lua script at path. A compile error here is
+// fatal, since it means the script can never run.
+func Load(path string) (*Engine, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	chunk, err := parse.Parse(bytes.NewReader(src), path)
+	if err != nil {
+		return nil, err
+	}
+	proto, err := lua.Compile(chunk, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Engine{proto: proto}, nil
+}
+
+// Handler returns an http.Handler that runs the engine's script for every
+// request. A script that errors or panics yields a 500 response; it never
+// takes down the process.
+func (e *Engine) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := e.run(w, r); err != nil {
+			log.Printf("scripts: %s %s: %v", r.Method, r.URL.Path, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	})
+}
+
+// run executes the script against a fresh *lua.LState, recovering from any
+// panic raised inside gopher-lua so a broken script can't crash the server.
+func (e *Engine) run(w http.ResponseWriter, r *http.Request) (runErr error) {
+	defer func() {
+		if p := recover(); p != nil {
+			runErr = &scriptError{p}
+		}
+	}()
+
+	L := lua.NewState()
+	defer L.Close()
+
+	state := &requestState{w: w, r: r}
+	registerAPI(L, state)
+
+	fn := L.NewFunctionFromProto(e.proto)
+	L.Push(fn)
+	if err := L.PCall(0, lua.MultRet, nil); err != nil {
+		return err
+	}
+
+	if !state.sent {
+		state.w.WriteHeader(http.StatusOK)
+		state.w.Write([]byte("Hello, World!\n"))
+	}
+	return nil
+}
+
+type scriptError struct{ value interface{} }
+
+func (e *scriptError) Error() string {
+	return "script panicked: " + toString(e.value)
+}
+
+func toString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	if err, ok := v.(error); ok {
+		return err.Error()
+	}
+	return "unknown error"
+}
+
+// requestState threads the in-flight request/response and "has a response
+// been sent yet" bit through to the Lua API functions.
+type requestState struct {
+	w    http.ResponseWriter
+	r    *http.Request
+	sent bool
+}
+
+// registerAPI installs the http, log, and rand tables the script can call
+// into: http.getRequestHeader, http.setResponseHeader, http.sendResponse,
+// http.sleep, log.info/warn/error, rand.intn.
+func registerAPI(L *lua.LState, state *requestState) {
+	httpTable := L.NewTable()
+	L.SetField(httpTable, "getRequestHeader", L.NewFunction(func(L *lua.LState) int {
+		name := L.CheckString(1)
+		L.Push(lua.LString(state.r.Header.Get(name)))
+		return 1
+	}))
+	L.SetField(httpTable, "setResponseHeader", L.NewFunction(func(L *lua.LState) int {
+		name := L.CheckString(1)
+		value := L.CheckString(2)
+		state.w.Header().Set(name, value)
+		return 0
+	}))
+	L.SetField(httpTable, "sendResponse", L.NewFunction(func(L *lua.LState) int {
+		status := L.CheckInt(1)
+		body := L.OptString(2, "")
+		state.w.WriteHeader(status)
+		state.w.Write([]byte(body))
+		state.sent = true
+		return 0
+	}))
+	L.SetField(httpTable, "sleep", L.NewFunction(func(L *lua.LState) int {
+		ms := L.CheckInt64(1)
+		time.Sleep(time.Duration(ms) * time.Millisecond)
+		return 0
+	}))
+	L.SetGlobal("http", httpTable)
+
+	logTable := L.NewTable()
+	L.SetField(logTable, "info", L.NewFunction(logFunc("INFO")))
+	L.SetField(logTable, "warn", L.NewFunction(logFunc("WARN")))
+	L.SetField(logTable, "error", L.NewFunction(logFunc("ERROR")))
+	L.SetGlobal("log", logTable)
+
+	randTable := L.NewTable()
+	L.SetField(randTable, "intn", L.NewFunction(func(L *lua.LState) int {
+		n := L.CheckInt(1)
+		L.Push(lua.LNumber(rand.Intn(n)))
+		return 1
+	}))
+	L.SetGlobal("rand", randTable)
+}
+
+func logFunc(level string) lua.LGFunction {
+	return func(L *lua.LState) int {
+		msg := L.CheckString(1)
+		log.Printf("[%s] %s", level, msg)
+		return 0
+	}
+}
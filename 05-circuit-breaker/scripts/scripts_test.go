@@ -0,0 +1,83 @@
+package scripts
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func load(t *testing.T, src string) *Engine {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "script.lua")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatalf("writing script: %v", err)
+	}
+	engine, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return engine
+}
+
+func TestHandlerSendResponse(t *testing.T) {
+	engine := load(t, `
+		http.setResponseHeader("X-Test", "yes")
+		http.sendResponse(201, "created")
+	`)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	engine.Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+	if got := rec.Header().Get("X-Test"); got != "yes" {
+		t.Errorf("X-Test header = %q, want %q", got, "yes")
+	}
+	if rec.Body.String() != "created" {
+		t.Errorf("body = %q, want %q", rec.Body.String(), "created")
+	}
+}
+
+func TestHandlerDefaultsToHelloWorld(t *testing.T) {
+	engine := load(t, `-- script that never calls http.sendResponse`)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	engine.Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestHandlerGetRequestHeader(t *testing.T) {
+	engine := load(t, `
+		local v = http.getRequestHeader("X-Probe")
+		http.sendResponse(200, v)
+	`)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Probe", "hello")
+	rec := httptest.NewRecorder()
+	engine.Handler().ServeHTTP(rec, req)
+
+	if rec.Body.String() != "hello" {
+		t.Errorf("body = %q, want %q", rec.Body.String(), "hello")
+	}
+}
+
+func TestHandlerFailingScriptYields500(t *testing.T) {
+	engine := load(t, `error("boom")`)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	engine.Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+}
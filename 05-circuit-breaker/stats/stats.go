@@ -0,0 +1,193 @@
+// Package stats instruments HTTP handlers with request counters, per-status
+// counts, in-flight gauges, and a response-time histogram, and renders the
+// result as JSON (mirroring thoas/stats) or Prometheus text exposition.
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultBuckets are the histogram bucket boundaries, in milliseconds, used
+// when a Recorder is created with NewRecorder.
+var DefaultBuckets = []float64{10, 50, 100, 250, 500, 1000}
+
+// Recorder accumulates request counters and a response-time histogram. The
+// totals, the in-flight gauge and the histogram buckets are updated with
+// atomics, so reading them never contends with request serving. The
+// per-status counters sit in a map guarded by statusMu: each request takes it
+// briefly to find its counter, and /stats and /metrics hold it while reading
+// the map.
+type Recorder struct {
+	buckets []float64 // sorted bucket boundaries, in milliseconds
+
+	totalCount        uint64
+	totalResponseTime uint64 // nanoseconds
+	inFlight          int64
+
+	statusMu     sync.Mutex
+	statusCounts map[int]*uint64
+
+	bucketCounts []uint64 // len(buckets)+1, last entry is the +Inf overflow bucket
+}
+
+// NewRecorder creates a Recorder using DefaultBuckets.
+func NewRecorder() *Recorder {
+	return NewRecorderWithBuckets(DefaultBuckets)
+}
+
+// NewRecorderWithBuckets creates a Recorder using the given histogram bucket
+// boundaries (in milliseconds). Boundaries need not be pre-sorted.
+func NewRecorderWithBuckets(buckets []float64) *Recorder {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	return &Recorder{
+		buckets:      sorted,
+		statusCounts: make(map[int]*uint64),
+		bucketCounts: make([]uint64, len(sorted)+1),
+	}
+}
+
+// Middleware wraps next, recording its status code, latency, and in-flight
+// count on r.
+func (r *Recorder) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt64(&r.inFlight, 1)
+		defer atomic.AddInt64(&r.inFlight, -1)
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(sw, req)
+		elapsed := time.Since(start)
+
+		atomic.AddUint64(&r.totalCount, 1)
+		atomic.AddUint64(&r.totalResponseTime, uint64(elapsed))
+		r.observeStatus(sw.status)
+		r.observeLatency(elapsed)
+	})
+}
+
+func (r *Recorder) observeStatus(status int) {
+	r.statusMu.Lock()
+	counter, ok := r.statusCounts[status]
+	if !ok {
+		counter = new(uint64)
+		r.statusCounts[status] = counter
+	}
+	r.statusMu.Unlock()
+	atomic.AddUint64(counter, 1)
+}
+
+func (r *Recorder) observeLatency(d time.Duration) {
+	ms := float64(d) / float64(time.Millisecond)
+	idx := sort.SearchFloat64s(r.buckets, ms)
+	atomic.AddUint64(&r.bucketCounts[idx], 1)
+}
+
+// statusWriter captures the status code written to an http.ResponseWriter,
+// treating an implicit (never-called) WriteHeader as 200 OK.
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Snapshot is the JSON shape returned by ServeStats, mirroring the fields
+// exposed by thoas/stats.
+type Snapshot struct {
+	TotalCount           uint64         `json:"total_count"`
+	TotalResponseTime    string         `json:"total_response_time"`
+	TotalStatusCodeCount map[string]int `json:"total_status_code_count"`
+	AverageResponseTime  string         `json:"average_response_time"`
+	CountPerStatusCode   map[string]int `json:"count_per_status_code"`
+}
+
+func (r *Recorder) snapshot() Snapshot {
+	total := atomic.LoadUint64(&r.totalCount)
+	totalTime := time.Duration(atomic.LoadUint64(&r.totalResponseTime))
+
+	var avg time.Duration
+	if total > 0 {
+		avg = totalTime / time.Duration(total)
+	}
+
+	r.statusMu.Lock()
+	counts := make(map[string]int, len(r.statusCounts))
+	for code, counter := range r.statusCounts {
+		counts[strconv.Itoa(code)] = int(atomic.LoadUint64(counter))
+	}
+	r.statusMu.Unlock()
+
+	return Snapshot{
+		TotalCount:           total,
+		TotalResponseTime:    totalTime.String(),
+		TotalStatusCodeCount: counts,
+		AverageResponseTime:  avg.String(),
+		CountPerStatusCode:   counts,
+	}
+}
+
+// ServeStats writes the current Snapshot as JSON.
+func (r *Recorder) ServeStats(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(r.snapshot())
+}
+
+// ServeMetrics writes the current counters in Prometheus text exposition
+// format.
+func (r *Recorder) ServeMetrics(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	fmt.Fprintf(w, "# HELP demo_requests_total Total number of requests handled.\n")
+	fmt.Fprintf(w, "# TYPE demo_requests_total counter\n")
+	fmt.Fprintf(w, "demo_requests_total %d\n", atomic.LoadUint64(&r.totalCount))
+
+	fmt.Fprintf(w, "# HELP demo_requests_in_flight Number of requests currently being served.\n")
+	fmt.Fprintf(w, "# TYPE demo_requests_in_flight gauge\n")
+	fmt.Fprintf(w, "demo_requests_in_flight %d\n", atomic.LoadInt64(&r.inFlight))
+
+	r.statusMu.Lock()
+	codes := make([]int, 0, len(r.statusCounts))
+	for code := range r.statusCounts {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	fmt.Fprintf(w, "# HELP demo_requests_status_total Total requests per status code.\n")
+	fmt.Fprintf(w, "# TYPE demo_requests_status_total counter\n")
+	for _, code := range codes {
+		count := atomic.LoadUint64(r.statusCounts[code])
+		fmt.Fprintf(w, "demo_requests_status_total{code=\"%d\"} %d\n", code, count)
+	}
+	r.statusMu.Unlock()
+
+	fmt.Fprintf(w, "# HELP demo_request_duration_milliseconds Request latency histogram.\n")
+	fmt.Fprintf(w, "# TYPE demo_request_duration_milliseconds histogram\n")
+	var cumulative uint64
+	for i, boundary := range r.buckets {
+		cumulative += atomic.LoadUint64(&r.bucketCounts[i])
+		fmt.Fprintf(w, "demo_request_duration_milliseconds_bucket{le=\"%g\"} %d\n", boundary, cumulative)
+	}
+	cumulative += atomic.LoadUint64(&r.bucketCounts[len(r.buckets)])
+	fmt.Fprintf(w, "demo_request_duration_milliseconds_bucket{le=\"+Inf\"} %d\n", cumulative)
+	fmt.Fprintf(w, "demo_request_duration_milliseconds_sum %d\n", atomic.LoadUint64(&r.totalResponseTime)/uint64(time.Millisecond))
+	fmt.Fprintf(w, "demo_request_duration_milliseconds_count %d\n", atomic.LoadUint64(&r.totalCount))
+}
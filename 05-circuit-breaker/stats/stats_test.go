@@ -0,0 +1,80 @@
+package stats
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestObserveLatencyBucketing(t *testing.T) {
+	buckets := []float64{10, 50, 100}
+
+	tests := []struct {
+		name    string
+		latency time.Duration
+		wantIdx int // index into bucketCounts that should be incremented
+	}{
+		{"below first boundary", 1 * time.Millisecond, 0},
+		{"on first boundary", 10 * time.Millisecond, 0},
+		{"between first and second", 20 * time.Millisecond, 1},
+		{"on last boundary", 100 * time.Millisecond, 2},
+		{"above every boundary", 500 * time.Millisecond, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRecorderWithBuckets(buckets)
+			r.observeLatency(tt.latency)
+
+			for i, count := range r.bucketCounts {
+				want := uint64(0)
+				if i == tt.wantIdx {
+					want = 1
+				}
+				if got := atomic.LoadUint64(&count); got != want {
+					t.Errorf("bucketCounts[%d] = %d, want %d", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMiddlewareCapturesImplicitStatus(t *testing.T) {
+	r := NewRecorder()
+	handler := r.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("ok")) // never calls WriteHeader
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	snap := r.snapshot()
+	if snap.TotalCount != 1 {
+		t.Fatalf("TotalCount = %d, want 1", snap.TotalCount)
+	}
+	if snap.CountPerStatusCode["200"] != 1 {
+		t.Fatalf("CountPerStatusCode[200] = %d, want 1", snap.CountPerStatusCode["200"])
+	}
+}
+
+func TestMiddlewareCapturesExplicitStatus(t *testing.T) {
+	r := NewRecorder()
+	handler := r.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	snap := r.snapshot()
+	if snap.CountPerStatusCode["404"] != 1 {
+		t.Fatalf("CountPerStatusCode[404] = %d, want 1", snap.CountPerStatusCode["404"])
+	}
+	if snap.CountPerStatusCode["200"] != 0 {
+		t.Fatalf("CountPerStatusCode[200] = %d, want 0", snap.CountPerStatusCode["200"])
+	}
+}